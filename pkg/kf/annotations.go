@@ -0,0 +1,142 @@
+package kf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	serving "github.com/knative/serving/pkg/apis/serving/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// AnnotationsClient interacts with an apps annotations. It should be
+// created via NewAnnotationsClient.
+type AnnotationsClient struct {
+	l AppLister
+	f ServingFactory
+}
+
+// NewAnnotationsClient creates a new AnnotationsClient.
+func NewAnnotationsClient(l AppLister, f ServingFactory) *AnnotationsClient {
+	return &AnnotationsClient{
+		l: l,
+		f: f,
+	}
+}
+
+// List fetches the annotations for an app.
+func (c *AnnotationsClient) List(appName string, opts ...ListAnnotationOption) (map[string]string, error) {
+	if appName == "" {
+		return nil, errors.New("invalid app name")
+	}
+	cfg := ListAnnotationOptionDefaults().Extend(opts).toConfig()
+
+	s, err := c.fetchAnnotationsService(cfg.Namespace, appName)
+	if err != nil {
+		return nil, err
+	}
+
+	results := map[string]string{}
+	for k, v := range s.ObjectMeta.Annotations {
+		results[k] = v
+	}
+
+	return results, nil
+}
+
+// Set sets annotations on an app, updating both the Service and its
+// revision template so service-level and revision-level metadata stay in
+// sync. Unlike labels, annotation keys have no Kubernetes-enforced
+// restriction beyond the usual qualified-name length limits, so no
+// reserved-prefix check is applied here, but keys are still validated
+// against those length/format rules.
+func (c *AnnotationsClient) Set(appName string, values map[string]string, opts ...SetAnnotationOption) error {
+	if appName == "" {
+		return errors.New("invalid app name")
+	}
+	if err := validateAnnotationKeys(values); err != nil {
+		return err
+	}
+	cfg := SetAnnotationOptionDefaults().Extend(opts).toConfig()
+
+	client, err := c.f()
+	if err != nil {
+		return err
+	}
+
+	s, err := c.fetchAnnotationsService(cfg.Namespace, appName)
+	if err != nil {
+		return err
+	}
+
+	if s.ObjectMeta.Annotations == nil {
+		s.ObjectMeta.Annotations = map[string]string{}
+	}
+	if s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Annotations == nil {
+		s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Annotations = map[string]string{}
+	}
+
+	for k, v := range values {
+		s.ObjectMeta.Annotations[k] = v
+		s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Annotations[k] = v
+	}
+
+	if _, err := client.Services(cfg.Namespace).Update(&s); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Unset removes annotations from an app.
+func (c *AnnotationsClient) Unset(appName string, names []string, opts ...UnsetAnnotationOption) error {
+	if appName == "" {
+		return errors.New("invalid app name")
+	}
+	cfg := UnsetAnnotationOptionDefaults().Extend(opts).toConfig()
+
+	client, err := c.f()
+	if err != nil {
+		return err
+	}
+
+	s, err := c.fetchAnnotationsService(cfg.Namespace, appName)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range names {
+		delete(s.ObjectMeta.Annotations, n)
+		delete(s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Annotations, n)
+	}
+
+	if _, err := client.Services(cfg.Namespace).Update(&s); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *AnnotationsClient) fetchAnnotationsService(namespace, appName string) (serving.Service, error) {
+	return fetchSingleService(c.l, namespace, appName)
+}
+
+// validateAnnotationKeys validates each key against Kubernetes' qualified
+// name rules (the length/format limits the Set doc comment refers to),
+// aggregating every problem found rather than stopping at the first.
+// Unlike labels, there's no reserved prefix to reject here.
+func validateAnnotationKeys(values map[string]string) error {
+	var errs []string
+
+	for k := range values {
+		if msgs := validation.IsQualifiedName(k); len(msgs) != 0 {
+			errs = append(errs, fmt.Sprintf("%q: %s", k, strings.Join(msgs, ", ")))
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("invalid annotation key(s):\n%s", strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
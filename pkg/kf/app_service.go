@@ -0,0 +1,26 @@
+package kf
+
+import (
+	"fmt"
+
+	serving "github.com/knative/serving/pkg/apis/serving/v1alpha1"
+)
+
+// fetchSingleService looks up the one Service belonging to appName in
+// namespace via l. AppLister filters by namespace and app name, so
+// anything other than exactly one result means the app doesn't exist.
+func fetchSingleService(l AppLister, namespace, appName string) (serving.Service, error) {
+	services, err := l.List(
+		WithListNamespace(namespace),
+		WithListAppName(appName),
+	)
+	if err != nil {
+		return serving.Service{}, err
+	}
+
+	if len(services) != 1 {
+		return serving.Service{}, fmt.Errorf("unknown app: '%s'", appName)
+	}
+
+	return services[0], nil
+}
@@ -0,0 +1,263 @@
+package kf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// EnvFileEntry is a single name/value pair parsed from an env file, in the
+// order it appeared in the file. Line is the 1-based line (dotenv) or
+// list position (YAML/JSON list) the entry came from, for reporting in
+// validation errors; it's 0 for entries parsed from a YAML/JSON map,
+// which has no meaningful position of its own.
+type EnvFileEntry struct {
+	Name   string
+	Source EnvSource
+	Line   int
+}
+
+// envFileParseError reports a single malformed line in an env file.
+type envFileParseError struct {
+	Line    int
+	Message string
+}
+
+func (e *envFileParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// SetFromFile reads env var definitions from path and applies them via
+// setFromOrdered, preserving the order names appeared in the file for any
+// that are new to the app (existing vars keep their current position, per
+// mergeEnvSources). The format (dotenv, YAML, or JSON) is detected from
+// the file's extension. Names are validated here, against entries that
+// still carry their file position, so a bad name is reported with its
+// line rather than as a bare name once it reaches setFromOrdered.
+func (c *EnvironmentClient) SetFromFile(appName, path string, opts ...SetEnvOption) error {
+	entries, err := ParseEnvFile(path)
+	if err != nil {
+		return err
+	}
+
+	cfg := SetEnvOptionDefaults().Extend(opts).toConfig()
+	if err := validateEnvFileEntryNames(entries, cfg.AllowDottedNames); err != nil {
+		return err
+	}
+
+	values, order := envFileValuesAndOrder(entries)
+
+	return c.setFromOrdered(appName, values, order, opts...)
+}
+
+// validateEnvFileEntryNames validates each entry's name with the same
+// rule SetFrom uses, but reports a failure against the line (or list
+// position) it came from instead of just the bare name, since by the
+// time setFromOrdered sees it the name has already been collapsed into a
+// map.
+func validateEnvFileEntryNames(entries []EnvFileEntry, allowDotted bool) error {
+	var errs []error
+
+	for _, e := range entries {
+		if err := validateEnvNames([]string{e.Name}, allowDotted); err != nil {
+			errs = append(errs, &envFileParseError{Line: e.Line, Message: err.Error()})
+		}
+	}
+
+	if len(errs) != 0 {
+		return utilerrors.NewAggregate(errs)
+	}
+
+	return nil
+}
+
+// envFileValuesAndOrder splits entries into the map SetFrom expects and
+// the order names first appeared in, so that names new to the app are
+// appended to Container.Env in file order rather than being collapsed
+// into an unordered map and re-sorted.
+func envFileValuesAndOrder(entries []EnvFileEntry) (map[string]EnvSource, []string) {
+	values := make(map[string]EnvSource, len(entries))
+	order := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if _, seen := values[e.Name]; !seen {
+			order = append(order, e.Name)
+		}
+		values[e.Name] = e.Source
+	}
+
+	return values, order
+}
+
+// ParseEnvFile reads and parses an env file, selecting a format based on
+// the file's extension: ".env" (or no extension) for dotenv, ".yml"/
+// ".yaml" for YAML, and ".json" for JSON.
+func ParseEnvFile(path string) ([]EnvFileEntry, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml":
+		return ParseYAMLEnvFile(data)
+	case ".json":
+		return ParseJSONEnvFile(data)
+	default:
+		return ParseDotenvEnvFile(data)
+	}
+}
+
+// ParseDotenvEnvFile parses dotenv-formatted data: "KEY=VALUE" lines,
+// blank lines and "#" comments are ignored, values may be quoted, and
+// "\n" escapes within double-quoted values are expanded. Single-quoted
+// values are taken literally.
+func ParseDotenvEnvFile(data []byte) ([]EnvFileEntry, error) {
+	var entries []EnvFileEntry
+	var errs []error
+
+	for i, rawLine := range strings.Split(string(data), "\n") {
+		line := strings.TrimSpace(rawLine)
+		lineNum := i + 1
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		idx := strings.Index(line, "=")
+		if idx < 0 {
+			errs = append(errs, &envFileParseError{Line: lineNum, Message: fmt.Sprintf("missing '=' in %q", line)})
+			continue
+		}
+
+		name := strings.TrimSpace(line[:idx])
+		value := unquoteDotenvValue(strings.TrimSpace(line[idx+1:]))
+
+		entries = append(entries, EnvFileEntry{Name: name, Source: EnvSource{Literal: value}, Line: lineNum})
+	}
+
+	if len(errs) != 0 {
+		return nil, utilerrors.NewAggregate(errs)
+	}
+
+	return entries, nil
+}
+
+func unquoteDotenvValue(value string) string {
+	if len(value) >= 2 {
+		if (value[0] == '"' && value[len(value)-1] == '"') || (value[0] == '\'' && value[len(value)-1] == '\'') {
+			quote := value[0]
+			value = value[1 : len(value)-1]
+			if quote == '"' {
+				if unquoted, err := strconv.Unquote(`"` + strings.ReplaceAll(value, `"`, `\"`) + `"`); err == nil {
+					return unquoted
+				}
+				value = strings.ReplaceAll(value, `\n`, "\n")
+			}
+		}
+	}
+
+	return value
+}
+
+// envFileYAMLEntry is the shape of a single YAML/JSON list entry, mirroring
+// the Kubernetes EnvVar/EnvVarSource fields.
+type envFileYAMLEntry struct {
+	Name      string `yaml:"name" json:"name"`
+	Value     string `yaml:"value" json:"value"`
+	ValueFrom *struct {
+		SecretKeyRef *struct {
+			Name string `yaml:"name" json:"name"`
+			Key  string `yaml:"key" json:"key"`
+		} `yaml:"secretKeyRef" json:"secretKeyRef"`
+		ConfigMapKeyRef *struct {
+			Name string `yaml:"name" json:"name"`
+			Key  string `yaml:"key" json:"key"`
+		} `yaml:"configMapKeyRef" json:"configMapKeyRef"`
+		FieldRef *struct {
+			FieldPath string `yaml:"fieldPath" json:"fieldPath"`
+		} `yaml:"fieldRef" json:"fieldRef"`
+	} `yaml:"valueFrom" json:"valueFrom"`
+}
+
+func (e envFileYAMLEntry) toEntry(line int) EnvFileEntry {
+	source := EnvSource{Literal: e.Value}
+	if e.ValueFrom != nil {
+		switch {
+		case e.ValueFrom.SecretKeyRef != nil:
+			source = EnvSource{SecretRef: &SecretEnvRef{Name: e.ValueFrom.SecretKeyRef.Name, Key: e.ValueFrom.SecretKeyRef.Key}}
+		case e.ValueFrom.ConfigMapKeyRef != nil:
+			source = EnvSource{ConfigMapRef: &ConfigMapEnvRef{Name: e.ValueFrom.ConfigMapKeyRef.Name, Key: e.ValueFrom.ConfigMapKeyRef.Key}}
+		case e.ValueFrom.FieldRef != nil:
+			source = EnvSource{FieldPath: e.ValueFrom.FieldRef.FieldPath}
+		}
+	}
+
+	return EnvFileEntry{Name: e.Name, Source: source, Line: line}
+}
+
+// ParseYAMLEnvFile parses YAML env data, accepting either a simple
+// map[string]string or an ordered list of entries (each with a "name" and
+// either a "value" or a "valueFrom" reference).
+func ParseYAMLEnvFile(data []byte) ([]EnvFileEntry, error) {
+	var list []envFileYAMLEntry
+	if err := yaml.Unmarshal(data, &list); err == nil && list != nil {
+		return envFileEntriesFromList(list), nil
+	}
+
+	var m map[string]string
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	return envFileEntriesFromMap(m), nil
+}
+
+// ParseJSONEnvFile parses JSON env data using the same shapes as
+// ParseYAMLEnvFile.
+func ParseJSONEnvFile(data []byte) ([]EnvFileEntry, error) {
+	var list []envFileYAMLEntry
+	if err := json.Unmarshal(data, &list); err == nil && list != nil {
+		return envFileEntriesFromList(list), nil
+	}
+
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	return envFileEntriesFromMap(m), nil
+}
+
+func envFileEntriesFromList(list []envFileYAMLEntry) []EnvFileEntry {
+	entries := make([]EnvFileEntry, 0, len(list))
+	for i, e := range list {
+		entries = append(entries, e.toEntry(i+1))
+	}
+
+	return entries
+}
+
+// envFileEntriesFromMap builds entries from an unordered map. Since maps
+// have no file-position to report, names are sorted for a stable, if
+// arbitrary, order.
+func envFileEntriesFromMap(m map[string]string) []EnvFileEntry {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entries := make([]EnvFileEntry, 0, len(names))
+	for _, name := range names {
+		entries = append(entries, EnvFileEntry{Name: name, Source: EnvSource{Literal: m[name]}})
+	}
+
+	return entries
+}
@@ -0,0 +1,171 @@
+package kf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenvEnvFile(t *testing.T) {
+	data := []byte(`
+# a comment
+FOO=bar
+BAZ="quoted value"
+ESCAPED="line one\nline two"
+SINGLE='single quoted'
+spring.datasource.url=jdbc:postgresql://host/db
+`)
+
+	got, err := ParseDotenvEnvFile(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []EnvFileEntry{
+		{Name: "FOO", Source: EnvSource{Literal: "bar"}, Line: 3},
+		{Name: "BAZ", Source: EnvSource{Literal: "quoted value"}, Line: 4},
+		{Name: "ESCAPED", Source: EnvSource{Literal: "line one\nline two"}, Line: 5},
+		{Name: "SINGLE", Source: EnvSource{Literal: "single quoted"}, Line: 6},
+		{Name: "spring.datasource.url", Source: EnvSource{Literal: "jdbc:postgresql://host/db"}, Line: 7},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want %#v, got %#v", want, got)
+	}
+}
+
+func TestParseDotenvEnvFileAggregatesLineErrors(t *testing.T) {
+	data := []byte("FOO=bar\nmissing-equals\nBAZ\n")
+
+	_, err := ParseDotenvEnvFile(data)
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	msg := err.Error()
+	if !strings.Contains(msg, "line 2") || !strings.Contains(msg, "line 3") {
+		t.Fatalf("expected errors for both malformed lines, got: %s", msg)
+	}
+}
+
+func TestParseYAMLEnvFileList(t *testing.T) {
+	data := []byte(`
+- name: FOO
+  value: bar
+- name: FROM_SECRET
+  valueFrom:
+    secretKeyRef:
+      name: mysecret
+      key: password
+`)
+
+	got, err := ParseYAMLEnvFile(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []EnvFileEntry{
+		{Name: "FOO", Source: EnvSource{Literal: "bar"}, Line: 1},
+		{Name: "FROM_SECRET", Source: EnvSource{SecretRef: &SecretEnvRef{Name: "mysecret", Key: "password"}}, Line: 2},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want %#v, got %#v", want, got)
+	}
+}
+
+func TestParseYAMLEnvFileMapIsSortedByName(t *testing.T) {
+	data := []byte("ZED: 1\nALPHA: 2\n")
+
+	got, err := ParseYAMLEnvFile(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []EnvFileEntry{
+		{Name: "ALPHA", Source: EnvSource{Literal: "2"}},
+		{Name: "ZED", Source: EnvSource{Literal: "1"}},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want %#v, got %#v", want, got)
+	}
+}
+
+func TestParseJSONEnvFileList(t *testing.T) {
+	data := []byte(`[{"name":"FOO","value":"bar"},{"name":"FROM_CM","valueFrom":{"configMapKeyRef":{"name":"mycm","key":"k"}}}]`)
+
+	got, err := ParseJSONEnvFile(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []EnvFileEntry{
+		{Name: "FOO", Source: EnvSource{Literal: "bar"}, Line: 1},
+		{Name: "FROM_CM", Source: EnvSource{ConfigMapRef: &ConfigMapEnvRef{Name: "mycm", Key: "k"}}, Line: 2},
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		t.Fatalf("want %#v, got %#v", want, got)
+	}
+}
+
+func TestEnvFileValuesAndOrderPreservesFileOrder(t *testing.T) {
+	// Deliberately not alphabetical, so a regression back to sorting the
+	// names (e.g. via a map) would be caught.
+	entries := []EnvFileEntry{
+		{Name: "ZED", Source: EnvSource{Literal: "1"}},
+		{Name: "ALPHA", Source: EnvSource{Literal: "2"}},
+		{Name: "MID", Source: EnvSource{Literal: "3"}},
+	}
+
+	values, order := envFileValuesAndOrder(entries)
+
+	wantOrder := []string{"ZED", "ALPHA", "MID"}
+	if !reflect.DeepEqual(wantOrder, order) {
+		t.Fatalf("want order %v, got %v", wantOrder, order)
+	}
+
+	wantValues := map[string]EnvSource{
+		"ZED":   {Literal: "1"},
+		"ALPHA": {Literal: "2"},
+		"MID":   {Literal: "3"},
+	}
+	if !reflect.DeepEqual(wantValues, values) {
+		t.Fatalf("want values %#v, got %#v", wantValues, values)
+	}
+}
+
+func TestValidateEnvFileEntryNamesReportsLineNumber(t *testing.T) {
+	entries := []EnvFileEntry{
+		{Name: "FOO", Line: 1},
+		{Name: "1bad", Line: 2},
+		{Name: "also bad", Line: 5},
+	}
+
+	err := validateEnvFileEntryNames(entries, false)
+	if err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	msg := err.Error()
+	if !strings.Contains(msg, "line 2") || !strings.Contains(msg, "line 5") {
+		t.Fatalf("expected errors tagged with their source line, got: %s", msg)
+	}
+	if strings.Contains(msg, "line 1:") {
+		t.Fatalf("did not expect an error for the valid name on line 1, got: %s", msg)
+	}
+}
+
+func TestParseEnvFileDoesNotValidateNames(t *testing.T) {
+	// Parsing must not reject dotted or otherwise non-C_IDENTIFIER names:
+	// that's deferred to SetFromFile, which knows whether WithAllowDottedNames
+	// was passed.
+	got, err := ParseDotenvEnvFile([]byte("spring.datasource.url=foo\n1bad=bar\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected parsing to pass through both names unvalidated, got: %#v", got)
+	}
+}
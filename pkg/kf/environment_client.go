@@ -3,9 +3,14 @@ package kf
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"sort"
+	"strings"
 
 	serving "github.com/knative/serving/pkg/apis/serving/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 // EnvironmentClient interacts with an apps environment variables. It should
@@ -23,7 +28,48 @@ func NewEnvironmentClient(l AppLister, f ServingFactory) *EnvironmentClient {
 	}
 }
 
-// List fetches the environment variables for an app.
+// SecretEnvRef points an env var at a key within a Secret.
+type SecretEnvRef struct {
+	Name string
+	Key  string
+}
+
+// ConfigMapEnvRef points an env var at a key within a ConfigMap.
+type ConfigMapEnvRef struct {
+	Name string
+	Key  string
+}
+
+// EnvSource describes where an environment variable's value comes from. It
+// is either a literal value or a reference to a Secret, ConfigMap, or a
+// downward-API field (e.g. "metadata.name"). Exactly one of Literal,
+// SecretRef, ConfigMapRef, or FieldPath should be populated.
+type EnvSource struct {
+	Literal      string
+	SecretRef    *SecretEnvRef
+	ConfigMapRef *ConfigMapEnvRef
+	FieldPath    string
+}
+
+// describe returns a short human readable description of the source,
+// suitable for displaying to a user via List.
+func (s EnvSource) describe() string {
+	switch {
+	case s.SecretRef != nil:
+		return fmt.Sprintf("secret:%s/%s", s.SecretRef.Name, s.SecretRef.Key)
+	case s.ConfigMapRef != nil:
+		return fmt.Sprintf("configmap:%s/%s", s.ConfigMapRef.Name, s.ConfigMapRef.Key)
+	case s.FieldPath != "":
+		return fmt.Sprintf("fieldRef:%s", s.FieldPath)
+	default:
+		return s.Literal
+	}
+}
+
+// List fetches the environment variables for an app. Variables sourced
+// from a Secret, ConfigMap, or the downward API are surfaced as a
+// descriptor (e.g. "secret:mysecret/key") rather than their (unknown at
+// list time) value.
 func (c *EnvironmentClient) List(appName string, opts ...ListEnvOption) (map[string]string, error) {
 	if appName == "" {
 		return nil, errors.New("invalid app name")
@@ -37,19 +83,58 @@ func (c *EnvironmentClient) List(appName string, opts ...ListEnvOption) (map[str
 
 	results := map[string]string{}
 	for _, env := range s.Spec.RunLatest.Configuration.RevisionTemplate.Spec.Container.Env {
-		results[env.Name] = env.Value
+		results[env.Name] = envVarToEnvSource(env).describe()
 	}
 
 	return results, err
 }
 
-// Set sets an environment variables for an app.
+// Set sets literal environment variables for an app. To reference a
+// Secret, ConfigMap, or downward-API field instead, use SetFrom.
 func (c *EnvironmentClient) Set(appName string, values map[string]string, opts ...SetEnvOption) error {
+	sources := make(map[string]EnvSource, len(values))
+	for n, v := range values {
+		sources[n] = EnvSource{Literal: v}
+	}
+
+	return c.SetFrom(appName, sources, opts...)
+}
+
+// SetFrom sets environment variables for an app, each of which may be a
+// literal value or a reference to a Secret, ConfigMap, or downward-API
+// field. Since values is a map, Go's iteration order is not guaranteed;
+// variables being added for the first time are appended in sorted name
+// order so repeated calls with the same input produce the same result.
+// Callers that need to preserve a specific order (e.g. SetFromFile,
+// preserving the order names appeared in a file) should use
+// setFromOrdered instead.
+func (c *EnvironmentClient) SetFrom(appName string, values map[string]EnvSource, opts ...SetEnvOption) error {
+	names := make([]string, 0, len(values))
+	for n := range values {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	return c.setFromOrdered(appName, values, names, opts...)
+}
+
+// setFromOrdered is SetFrom with an explicit order in which newly-added
+// names should be appended, for callers (SetFromFile) that have a
+// meaningful order of their own rather than Go's unordered map iteration.
+func (c *EnvironmentClient) setFromOrdered(appName string, values map[string]EnvSource, order []string, opts ...SetEnvOption) error {
 	if appName == "" {
 		return errors.New("invalid app name")
 	}
 	cfg := SetEnvOptionDefaults().Extend(opts).toConfig()
 
+	names := make([]string, 0, len(values))
+	for n := range values {
+		names = append(names, n)
+	}
+	if err := validateEnvNames(names, cfg.AllowDottedNames); err != nil {
+		return err
+	}
+
 	client, err := c.f()
 	if err != nil {
 		return err
@@ -60,12 +145,12 @@ func (c *EnvironmentClient) Set(appName string, values map[string]string, opts .
 		return err
 	}
 
-	newValues := c.dedupeEnvs(
+	s.Spec.RunLatest.Configuration.RevisionTemplate.Spec.Container.Env = c.mergeEnvSources(
 		values,
+		order,
 		s.Spec.RunLatest.Configuration.RevisionTemplate.Spec.Container.Env,
 	)
 
-	s.Spec.RunLatest.Configuration.RevisionTemplate.Spec.Container.Env = c.mapToEnvs(newValues)
 	if _, err := client.Services(cfg.Namespace).Update(&s); err != nil {
 		return err
 	}
@@ -80,6 +165,10 @@ func (c *EnvironmentClient) Unset(appName string, names []string, opts ...UnsetE
 	}
 	cfg := UnsetEnvOptionDefaults().Extend(opts).toConfig()
 
+	if err := validateEnvNames(names, cfg.AllowDottedNames); err != nil {
+		return err
+	}
+
 	client, err := c.f()
 	if err != nil {
 		return err
@@ -120,40 +209,141 @@ func (c *EnvironmentClient) removeEnvs(names []string, envs []corev1.EnvVar) []c
 	return newValues
 }
 
-func (c *EnvironmentClient) dedupeEnvs(values map[string]string, envs []corev1.EnvVar) map[string]string {
-	// Create a new map so that we can prioritize the new values over the
-	// existing.
-	newValues := map[string]string{}
+// mergeEnvSources layers values on top of envs, with values taking
+// priority for any name present in both. Existing env vars keep their
+// original position so that unrelated Set/Unset calls don't reshuffle
+// Container.Env (which would otherwise trigger a spurious Knative
+// revision and could break vars that reference each other via
+// "$(VAR)" expansion). Vars being added for the first time are appended
+// in the order they appear in newNameOrder, skipping any name already
+// present in envs; it's the caller's responsibility to pass an order
+// that covers every new name (SetFrom sorts it, setFromOrdered/
+// SetFromFile preserve a caller- or file-given order).
+func (c *EnvironmentClient) mergeEnvSources(values map[string]EnvSource, newNameOrder []string, envs []corev1.EnvVar) []corev1.EnvVar {
+	merged := map[string]EnvSource{}
+	for _, e := range envs {
+		merged[e.Name] = envVarToEnvSource(e)
+	}
+
+	order := make([]string, 0, len(envs)+len(values))
 	for _, e := range envs {
-		newValues[e.Name] = e.Value
+		order = append(order, e.Name)
 	}
+
+	for _, n := range newNameOrder {
+		if _, ok := merged[n]; !ok {
+			order = append(order, n)
+		}
+	}
+
 	for n, v := range values {
-		newValues[n] = v
+		merged[n] = v
+	}
+
+	newValues := make([]corev1.EnvVar, 0, len(order))
+	for _, n := range order {
+		newValues = append(newValues, envSourceToEnvVar(n, merged[n]))
 	}
 
 	return newValues
 }
 
-func (c *EnvironmentClient) mapToEnvs(values map[string]string) []corev1.EnvVar {
-	var envs []corev1.EnvVar
-	for n, v := range values {
-		envs = append(envs, corev1.EnvVar{Name: n, Value: v})
+func envSourceToEnvVar(name string, s EnvSource) corev1.EnvVar {
+	env := corev1.EnvVar{Name: name}
+
+	switch {
+	case s.SecretRef != nil:
+		env.ValueFrom = &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: s.SecretRef.Name},
+				Key:                  s.SecretRef.Key,
+			},
+		}
+	case s.ConfigMapRef != nil:
+		env.ValueFrom = &corev1.EnvVarSource{
+			ConfigMapKeyRef: &corev1.ConfigMapKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: s.ConfigMapRef.Name},
+				Key:                  s.ConfigMapRef.Key,
+			},
+		}
+	case s.FieldPath != "":
+		env.ValueFrom = &corev1.EnvVarSource{
+			FieldRef: &corev1.ObjectFieldSelector{FieldPath: s.FieldPath},
+		}
+	default:
+		env.Value = s.Literal
 	}
-	return envs
+
+	return env
 }
 
-func (c *EnvironmentClient) fetchService(namespace, appName string) (serving.Service, error) {
-	services, err := c.l.List(
-		WithListNamespace(namespace),
-		WithListAppName(appName),
-	)
-	if err != nil {
-		return serving.Service{}, err
+func envVarToEnvSource(env corev1.EnvVar) EnvSource {
+	if env.ValueFrom == nil {
+		return EnvSource{Literal: env.Value}
+	}
+
+	switch {
+	case env.ValueFrom.SecretKeyRef != nil:
+		return EnvSource{SecretRef: &SecretEnvRef{
+			Name: env.ValueFrom.SecretKeyRef.Name,
+			Key:  env.ValueFrom.SecretKeyRef.Key,
+		}}
+	case env.ValueFrom.ConfigMapKeyRef != nil:
+		return EnvSource{ConfigMapRef: &ConfigMapEnvRef{
+			Name: env.ValueFrom.ConfigMapKeyRef.Name,
+			Key:  env.ValueFrom.ConfigMapKeyRef.Key,
+		}}
+	case env.ValueFrom.FieldRef != nil:
+		return EnvSource{FieldPath: env.ValueFrom.FieldRef.FieldPath}
+	}
+
+	return EnvSource{}
+}
+
+// InvalidEnvNameError reports a single env var name that failed
+// validation.
+type InvalidEnvNameError struct {
+	Name   string
+	Reason string
+}
+
+func (e InvalidEnvNameError) Error() string {
+	return fmt.Sprintf("invalid env var name %q: %s", e.Name, e.Reason)
+}
+
+// dottedEnvNameRegexp relaxes the strict C_IDENTIFIER rule to also allow
+// "." and "-", matching the permissive rule OpenShift's "oc new-app"
+// uses so Spring-style names like "spring.datasource.url" are accepted.
+var dottedEnvNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+// validateEnvNames checks every name against the strict C_IDENTIFIER rule,
+// validation.IsCIdentifier (or, if allowDotted is set, the more permissive
+// dotted-name rule), returning
+// a single aggregated error listing every bad name so a caller sees every
+// problem before any API call is made.
+func validateEnvNames(names []string, allowDotted bool) error {
+	var errs []error
+
+	for _, name := range names {
+		if allowDotted {
+			if !dottedEnvNameRegexp.MatchString(name) {
+				errs = append(errs, InvalidEnvNameError{Name: name, Reason: "must consist of alphanumeric characters, '_', '-' or '.', and must start with a letter or '_'"})
+			}
+			continue
+		}
+
+		if msgs := validation.IsCIdentifier(name); len(msgs) != 0 {
+			errs = append(errs, InvalidEnvNameError{Name: name, Reason: strings.Join(msgs, ", ")})
+		}
 	}
 
-	if len(services) != 1 {
-		return serving.Service{}, fmt.Errorf("unknown app: '%s'", appName)
+	if len(errs) != 0 {
+		return utilerrors.NewAggregate(errs)
 	}
 
-	return services[0], nil
+	return nil
+}
+
+func (c *EnvironmentClient) fetchService(namespace, appName string) (serving.Service, error) {
+	return fetchSingleService(c.l, namespace, appName)
 }
@@ -0,0 +1,126 @@
+package kf
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestEnvironmentClientMergeEnvSourcesDeterministic(t *testing.T) {
+	c := &EnvironmentClient{}
+
+	values := map[string]EnvSource{
+		"FOO": {Literal: "foo"},
+		"BAR": {Literal: "bar"},
+		"BAZ": {Literal: "baz"},
+	}
+
+	order := []string{"BAR", "BAZ", "FOO"}
+
+	var envs []corev1.EnvVar
+	var first []corev1.EnvVar
+
+	for i := 0; i < 100; i++ {
+		envs = c.mergeEnvSources(values, order, envs)
+
+		if i == 0 {
+			first = envs
+			continue
+		}
+
+		if !reflect.DeepEqual(first, envs) {
+			t.Fatalf("iteration %d: env list changed after the first Set with identical input\nwant: %#v\ngot:  %#v", i, first, envs)
+		}
+	}
+}
+
+func TestEnvironmentClientMergeEnvSourcesPreservesExistingOrder(t *testing.T) {
+	c := &EnvironmentClient{}
+
+	existing := []corev1.EnvVar{
+		{Name: "ZED", Value: "1"},
+		{Name: "ALPHA", Value: "2"},
+	}
+
+	got := c.mergeEnvSources(map[string]EnvSource{
+		"ALPHA": {Literal: "updated"},
+		"NEW":   {Literal: "3"},
+	}, []string{"ALPHA", "NEW"}, existing)
+
+	want := []string{"ZED", "ALPHA", "NEW"}
+	var gotNames []string
+	for _, e := range got {
+		gotNames = append(gotNames, e.Name)
+	}
+
+	if !reflect.DeepEqual(want, gotNames) {
+		t.Fatalf("want order %v, got %v", want, gotNames)
+	}
+
+	for _, e := range got {
+		if e.Name == "ALPHA" && e.Value != "updated" {
+			t.Fatalf("ALPHA was not updated in place: %+v", e)
+		}
+	}
+}
+
+func TestEnvironmentClientMergeEnvSourcesHonorsExplicitNewNameOrder(t *testing.T) {
+	c := &EnvironmentClient{}
+
+	got := c.mergeEnvSources(map[string]EnvSource{
+		"ALPHA": {Literal: "1"},
+		"ZETA":  {Literal: "2"},
+	}, []string{"ZETA", "ALPHA"}, nil)
+
+	want := []string{"ZETA", "ALPHA"}
+	var gotNames []string
+	for _, e := range got {
+		gotNames = append(gotNames, e.Name)
+	}
+
+	if !reflect.DeepEqual(want, gotNames) {
+		t.Fatalf("want order %v (as given, not alphabetical), got %v", want, gotNames)
+	}
+}
+
+func TestValidateEnvNames(t *testing.T) {
+	cases := map[string]struct {
+		names       []string
+		allowDotted bool
+		wantErr     bool
+	}{
+		"valid C_IDENTIFIER names": {
+			names: []string{"FOO", "_BAR", "baz9"},
+		},
+		"dotted name rejected by default": {
+			names:   []string{"spring.datasource.url"},
+			wantErr: true,
+		},
+		"dotted name allowed when opted in": {
+			names:       []string{"spring.datasource.url", "my-app.port"},
+			allowDotted: true,
+		},
+		"leading digit rejected even when dotted names are allowed": {
+			names:       []string{"9lives"},
+			allowDotted: true,
+			wantErr:     true,
+		},
+		"all bad names are aggregated": {
+			names:   []string{"1bad", "also bad"},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := validateEnvNames(tc.names, tc.allowDotted)
+			if tc.wantErr && err == nil {
+				t.Fatalf("expected an error, got nil")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package kf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	serving "github.com/knative/serving/pkg/apis/serving/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// reservedLabelPrefix is the prefix Knative uses for its own bookkeeping
+// labels/annotations. Users can't Set keys under it because doing so could
+// corrupt the Service's interaction with the serving controller.
+const reservedLabelPrefix = "serving.knative.dev/"
+
+// LabelsClient interacts with an apps labels. It should be created via
+// NewLabelsClient.
+type LabelsClient struct {
+	l AppLister
+	f ServingFactory
+}
+
+// NewLabelsClient creates a new LabelsClient.
+func NewLabelsClient(l AppLister, f ServingFactory) *LabelsClient {
+	return &LabelsClient{
+		l: l,
+		f: f,
+	}
+}
+
+// List fetches the labels for an app.
+func (c *LabelsClient) List(appName string, opts ...ListLabelOption) (map[string]string, error) {
+	if appName == "" {
+		return nil, errors.New("invalid app name")
+	}
+	cfg := ListLabelOptionDefaults().Extend(opts).toConfig()
+
+	s, err := c.fetchLabelsService(cfg.Namespace, appName)
+	if err != nil {
+		return nil, err
+	}
+
+	results := map[string]string{}
+	for k, v := range s.ObjectMeta.Labels {
+		results[k] = v
+	}
+
+	return results, nil
+}
+
+// Set sets labels on an app, updating both the Service and its revision
+// template so service-level and revision-level metadata stay in sync.
+func (c *LabelsClient) Set(appName string, values map[string]string, opts ...SetLabelOption) error {
+	if appName == "" {
+		return errors.New("invalid app name")
+	}
+	if err := validateLabelKeys(values); err != nil {
+		return err
+	}
+	cfg := SetLabelOptionDefaults().Extend(opts).toConfig()
+
+	client, err := c.f()
+	if err != nil {
+		return err
+	}
+
+	s, err := c.fetchLabelsService(cfg.Namespace, appName)
+	if err != nil {
+		return err
+	}
+
+	if s.ObjectMeta.Labels == nil {
+		s.ObjectMeta.Labels = map[string]string{}
+	}
+	if s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Labels == nil {
+		s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Labels = map[string]string{}
+	}
+
+	for k, v := range values {
+		s.ObjectMeta.Labels[k] = v
+		s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Labels[k] = v
+	}
+
+	if _, err := client.Services(cfg.Namespace).Update(&s); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Unset removes labels from an app.
+func (c *LabelsClient) Unset(appName string, names []string, opts ...UnsetLabelOption) error {
+	if appName == "" {
+		return errors.New("invalid app name")
+	}
+	cfg := UnsetLabelOptionDefaults().Extend(opts).toConfig()
+
+	client, err := c.f()
+	if err != nil {
+		return err
+	}
+
+	s, err := c.fetchLabelsService(cfg.Namespace, appName)
+	if err != nil {
+		return err
+	}
+
+	for _, n := range names {
+		delete(s.ObjectMeta.Labels, n)
+		delete(s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Labels, n)
+	}
+
+	if _, err := client.Services(cfg.Namespace).Update(&s); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (c *LabelsClient) fetchLabelsService(namespace, appName string) (serving.Service, error) {
+	return fetchSingleService(c.l, namespace, appName)
+}
+
+// validateLabelKeys validates each key against Kubernetes' label-key
+// rules and rejects the reserved serving.knative.dev/ prefix, aggregating
+// every problem found rather than stopping at the first.
+func validateLabelKeys(values map[string]string) error {
+	var errs []string
+
+	for k := range values {
+		if strings.HasPrefix(k, reservedLabelPrefix) {
+			errs = append(errs, fmt.Sprintf("%q: reserved prefix %q is not allowed", k, reservedLabelPrefix))
+			continue
+		}
+		if msgs := validation.IsQualifiedName(k); len(msgs) != 0 {
+			errs = append(errs, fmt.Sprintf("%q: %s", k, strings.Join(msgs, ", ")))
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("invalid label key(s):\n%s", strings.Join(errs, "\n"))
+	}
+
+	return nil
+}
@@ -0,0 +1,278 @@
+package kf
+
+import (
+	"errors"
+	"sort"
+
+	serving "github.com/knative/serving/pkg/apis/serving/v1alpha1"
+
+	"github.com/jadhav-kumar/kf/pkg/retry"
+)
+
+// AppMutator batches env, label, and annotation changes for a single app
+// into one Services().Update call, instead of each Set/Unset issuing its
+// own fetch-modify-write (and therefore its own Knative revision and its
+// own race window). Create one via NewAppMutator, scope it to an app with
+// For, queue up changes, then call Apply.
+type AppMutator struct {
+	l AppLister
+	f ServingFactory
+
+	appName   string
+	namespace string
+
+	allowDottedNames bool
+
+	setEnv           map[string]EnvSource
+	unsetEnv         []string
+	setLabels        map[string]string
+	unsetLabels      []string
+	setAnnotations   map[string]string
+	unsetAnnotations []string
+}
+
+// NewAppMutator creates a new AppMutator.
+func NewAppMutator(l AppLister, f ServingFactory) *AppMutator {
+	return &AppMutator{l: l, f: f}
+}
+
+// For scopes the mutator to a single app, returning a fresh builder so
+// that a shared AppMutator can be reused across apps without its queued
+// changes bleeding between them.
+func (m *AppMutator) For(appName string, opts ...MutatorOption) *AppMutator {
+	cfg := MutatorOptionDefaults().Extend(opts).toConfig()
+
+	return &AppMutator{
+		l:                m.l,
+		f:                m.f,
+		appName:          appName,
+		namespace:        cfg.Namespace,
+		allowDottedNames: cfg.AllowDottedNames,
+	}
+}
+
+// SetEnv queues literal environment variables to be set. For any name
+// also passed to UnsetEnv on this mutator, whichever call happens last
+// wins.
+func (m *AppMutator) SetEnv(values map[string]string) *AppMutator {
+	if m.setEnv == nil {
+		m.setEnv = map[string]EnvSource{}
+	}
+	for n, v := range values {
+		m.unsetEnv = removeName(m.unsetEnv, n)
+		m.setEnv[n] = EnvSource{Literal: v}
+	}
+	return m
+}
+
+// UnsetEnv queues environment variables to be removed. For any name also
+// passed to SetEnv on this mutator, whichever call happens last wins.
+func (m *AppMutator) UnsetEnv(names []string) *AppMutator {
+	for _, n := range names {
+		delete(m.setEnv, n)
+	}
+	m.unsetEnv = append(m.unsetEnv, names...)
+	return m
+}
+
+// SetLabel queues a single label to be set.
+func (m *AppMutator) SetLabel(key, value string) *AppMutator {
+	return m.SetLabels(map[string]string{key: value})
+}
+
+// SetLabels queues labels to be set. For any key also passed to
+// UnsetLabels on this mutator, whichever call happens last wins.
+func (m *AppMutator) SetLabels(values map[string]string) *AppMutator {
+	if m.setLabels == nil {
+		m.setLabels = map[string]string{}
+	}
+	for k, v := range values {
+		m.unsetLabels = removeName(m.unsetLabels, k)
+		m.setLabels[k] = v
+	}
+	return m
+}
+
+// UnsetLabels queues labels to be removed. For any key also passed to
+// SetLabels/SetLabel on this mutator, whichever call happens last wins.
+func (m *AppMutator) UnsetLabels(names []string) *AppMutator {
+	for _, n := range names {
+		delete(m.setLabels, n)
+	}
+	m.unsetLabels = append(m.unsetLabels, names...)
+	return m
+}
+
+// SetAnnotation queues a single annotation to be set.
+func (m *AppMutator) SetAnnotation(key, value string) *AppMutator {
+	return m.SetAnnotations(map[string]string{key: value})
+}
+
+// SetAnnotations queues annotations to be set. For any key also passed
+// to UnsetAnnotations on this mutator, whichever call happens last wins.
+func (m *AppMutator) SetAnnotations(values map[string]string) *AppMutator {
+	if m.setAnnotations == nil {
+		m.setAnnotations = map[string]string{}
+	}
+	for k, v := range values {
+		m.unsetAnnotations = removeName(m.unsetAnnotations, k)
+		m.setAnnotations[k] = v
+	}
+	return m
+}
+
+// UnsetAnnotations queues annotations to be removed. For any key also
+// passed to SetAnnotations/SetAnnotation on this mutator, whichever call
+// happens last wins.
+func (m *AppMutator) UnsetAnnotations(names []string) *AppMutator {
+	for _, n := range names {
+		delete(m.setAnnotations, n)
+	}
+	m.unsetAnnotations = append(m.unsetAnnotations, names...)
+	return m
+}
+
+// removeName returns names with every occurrence of target removed, so
+// a later Set call can retract an earlier, contradicting Unset (or vice
+// versa) and leave the mutator's queued changes internally consistent.
+func removeName(names []string, target string) []string {
+	out := names[:0:0]
+	for _, n := range names {
+		if n != target {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// Apply fetches the app's Service, applies all queued changes, and writes
+// the result back in a single Update call. If the Update is rejected due
+// to a conflicting concurrent write, Apply re-fetches and re-applies the
+// queued changes, up to a bounded number of attempts.
+func (m *AppMutator) Apply() error {
+	if m.appName == "" {
+		return errors.New("invalid app name")
+	}
+
+	client, err := m.f()
+	if err != nil {
+		return err
+	}
+
+	return retry.OnConflict(func() error {
+		s, err := m.fetchService()
+		if err != nil {
+			return err
+		}
+
+		updated, err := m.mutate(s)
+		if err != nil {
+			return err
+		}
+
+		_, err = client.Services(m.namespace).Update(&updated)
+		return err
+	})
+}
+
+// DryRun fetches the app's Service and returns both the unmodified
+// Service and the Service as it would look after applying all queued
+// changes, without writing anything.
+func (m *AppMutator) DryRun() (serving.Service, serving.Service, error) {
+	if m.appName == "" {
+		return serving.Service{}, serving.Service{}, errors.New("invalid app name")
+	}
+
+	s, err := m.fetchService()
+	if err != nil {
+		return serving.Service{}, serving.Service{}, err
+	}
+
+	updated, err := m.mutate(s)
+	if err != nil {
+		return serving.Service{}, serving.Service{}, err
+	}
+
+	return s, updated, nil
+}
+
+// mutate returns a copy of s with all queued env, label, and annotation
+// changes applied, after validating every env name, label key, and
+// annotation key queued to be set so a bad queued value is caught here
+// rather than producing an obscure admission error from the Update call in
+// Apply. Names and keys queued to be unset are not validated.
+func (m *AppMutator) mutate(s serving.Service) (serving.Service, error) {
+	envNames := make([]string, 0, len(m.setEnv))
+	for n := range m.setEnv {
+		envNames = append(envNames, n)
+	}
+	if err := validateEnvNames(envNames, m.allowDottedNames); err != nil {
+		return serving.Service{}, err
+	}
+	if err := validateLabelKeys(m.setLabels); err != nil {
+		return serving.Service{}, err
+	}
+	if err := validateAnnotationKeys(m.setAnnotations); err != nil {
+		return serving.Service{}, err
+	}
+
+	// Work on a deep copy so the caller's Service (e.g. the "before" that
+	// DryRun returns) isn't changed through shared maps or pointers.
+	s = *s.DeepCopy()
+	if s.Spec.RunLatest == nil {
+		s.Spec.RunLatest = &serving.RunLatestType{}
+	}
+
+	setEnvOrder := make([]string, 0, len(m.setEnv))
+	for n := range m.setEnv {
+		setEnvOrder = append(setEnvOrder, n)
+	}
+	sort.Strings(setEnvOrder)
+
+	env := &EnvironmentClient{}
+	s.Spec.RunLatest.Configuration.RevisionTemplate.Spec.Container.Env = env.mergeEnvSources(
+		m.setEnv,
+		setEnvOrder,
+		s.Spec.RunLatest.Configuration.RevisionTemplate.Spec.Container.Env,
+	)
+	s.Spec.RunLatest.Configuration.RevisionTemplate.Spec.Container.Env = env.removeEnvs(
+		m.unsetEnv,
+		s.Spec.RunLatest.Configuration.RevisionTemplate.Spec.Container.Env,
+	)
+
+	if s.ObjectMeta.Labels == nil {
+		s.ObjectMeta.Labels = map[string]string{}
+	}
+	if s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Labels == nil {
+		s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Labels = map[string]string{}
+	}
+	for k, v := range m.setLabels {
+		s.ObjectMeta.Labels[k] = v
+		s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Labels[k] = v
+	}
+	for _, k := range m.unsetLabels {
+		delete(s.ObjectMeta.Labels, k)
+		delete(s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Labels, k)
+	}
+
+	if s.ObjectMeta.Annotations == nil {
+		s.ObjectMeta.Annotations = map[string]string{}
+	}
+	if s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Annotations == nil {
+		s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Annotations = map[string]string{}
+	}
+	for k, v := range m.setAnnotations {
+		s.ObjectMeta.Annotations[k] = v
+		s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Annotations[k] = v
+	}
+	for _, k := range m.unsetAnnotations {
+		delete(s.ObjectMeta.Annotations, k)
+		delete(s.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Annotations, k)
+	}
+
+	return s, nil
+}
+
+func (m *AppMutator) fetchService() (serving.Service, error) {
+	return fetchSingleService(m.l, m.namespace, m.appName)
+}
@@ -0,0 +1,99 @@
+package kf
+
+import (
+	"testing"
+
+	serving "github.com/knative/serving/pkg/apis/serving/v1alpha1"
+)
+
+func TestAppMutatorMutateAppliesQueuedChanges(t *testing.T) {
+	m := (&AppMutator{}).
+		SetEnv(map[string]string{"FOO": "bar"}).
+		SetLabel("team", "payments").
+		SetAnnotation("owner", "payments-team")
+
+	var s serving.Service
+	got, err := m.mutate(s)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	env := got.Spec.RunLatest.Configuration.RevisionTemplate.Spec.Container.Env
+	if len(env) != 1 || env[0].Name != "FOO" || env[0].Value != "bar" {
+		t.Fatalf("env not applied: %#v", env)
+	}
+
+	if got.ObjectMeta.Labels["team"] != "payments" {
+		t.Fatalf("label not applied: %#v", got.ObjectMeta.Labels)
+	}
+	if got.Spec.RunLatest.Configuration.RevisionTemplate.ObjectMeta.Labels["team"] != "payments" {
+		t.Fatalf("label not applied to revision template")
+	}
+
+	if got.ObjectMeta.Annotations["owner"] != "payments-team" {
+		t.Fatalf("annotation not applied: %#v", got.ObjectMeta.Annotations)
+	}
+}
+
+func TestAppMutatorMutateLastCallWinsForConflictingEnvOps(t *testing.T) {
+	m := (&AppMutator{}).
+		UnsetEnv([]string{"FOO"}).
+		SetEnv(map[string]string{"FOO": "bar"})
+
+	got, err := m.mutate(serving.Service{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	env := got.Spec.RunLatest.Configuration.RevisionTemplate.Spec.Container.Env
+	if len(env) != 1 || env[0].Name != "FOO" || env[0].Value != "bar" {
+		t.Fatalf("expected the later SetEnv to win over the earlier UnsetEnv, got: %#v", env)
+	}
+
+	m2 := (&AppMutator{}).
+		SetEnv(map[string]string{"FOO": "bar"}).
+		UnsetEnv([]string{"FOO"})
+
+	got2, err := m2.mutate(serving.Service{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	env2 := got2.Spec.RunLatest.Configuration.RevisionTemplate.Spec.Container.Env
+	if len(env2) != 0 {
+		t.Fatalf("expected the later UnsetEnv to win over the earlier SetEnv, got: %#v", env2)
+	}
+}
+
+func TestAppMutatorMutateRejectsInvalidEnvName(t *testing.T) {
+	m := (&AppMutator{}).SetEnv(map[string]string{"bad name!": "x"})
+
+	if _, err := m.mutate(serving.Service{}); err == nil {
+		t.Fatalf("expected an error for an invalid env var name")
+	}
+}
+
+func TestAppMutatorMutateAllowsDottedEnvNameWhenOptedIn(t *testing.T) {
+	m := &AppMutator{allowDottedNames: true}
+	m.SetEnv(map[string]string{"spring.datasource.url": "jdbc:postgresql://host/db"})
+
+	if _, err := m.mutate(serving.Service{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestAppMutatorMutateRejectsReservedLabelPrefix(t *testing.T) {
+	m := (&AppMutator{}).SetLabel("serving.knative.dev/foo", "x")
+
+	if _, err := m.mutate(serving.Service{}); err == nil {
+		t.Fatalf("expected an error for a reserved label prefix")
+	}
+}
+
+func TestAppMutatorMutateRejectsInvalidAnnotationKey(t *testing.T) {
+	m := (&AppMutator{}).SetAnnotation("not a valid key!", "x")
+
+	if _, err := m.mutate(serving.Service{}); err == nil {
+		t.Fatalf("expected an error for an invalid annotation key")
+	}
+}
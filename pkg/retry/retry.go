@@ -0,0 +1,59 @@
+// Package retry provides a bounded, exponential-backoff retry loop for
+// operations that can fail with an optimistic-concurrency conflict, e.g.
+// a fetch-mutate-update against the Kubernetes API.
+package retry
+
+import (
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Options configures OnConflict's retry loop.
+type Options struct {
+	// MaxAttempts is the maximum number of times fn is invoked.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the second attempt. It doubles
+	// after each subsequent attempt.
+	InitialBackoff time.Duration
+}
+
+// DefaultOptions returns the Options used by OnConflict.
+func DefaultOptions() Options {
+	return Options{
+		MaxAttempts:    5,
+		InitialBackoff: 100 * time.Millisecond,
+	}
+}
+
+// OnConflict invokes fn, retrying with exponential backoff (via
+// DefaultOptions) as long as fn returns a Kubernetes "conflict" error
+// (i.e. apierrors.IsConflict). Any other error is returned immediately.
+func OnConflict(fn func() error) error {
+	return OnConflictWithOptions(DefaultOptions(), fn)
+}
+
+// OnConflictWithOptions is OnConflict with caller-supplied Options.
+func OnConflictWithOptions(opts Options, fn func() error) error {
+	backoff := opts.InitialBackoff
+
+	var err error
+	for attempt := 0; attempt < opts.MaxAttempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if !apierrors.IsConflict(err) {
+			return err
+		}
+		if attempt == opts.MaxAttempts-1 {
+			break
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	return fmt.Errorf("giving up after %d attempts due to repeated conflicts: %v", opts.MaxAttempts, err)
+}
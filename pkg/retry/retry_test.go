@@ -0,0 +1,86 @@
+package retry
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var conflictErr = apierrors.NewConflict(schema.GroupResource{Resource: "services"}, "myapp", errors.New("conflict"))
+
+func testOptions() Options {
+	return Options{MaxAttempts: 3, InitialBackoff: time.Millisecond}
+}
+
+func TestOnConflictWithOptionsRetriesUntilSuccess(t *testing.T) {
+	attempts := 0
+	err := OnConflictWithOptions(testOptions(), func() error {
+		attempts++
+		if attempts < 2 {
+			return conflictErr
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 2 {
+		t.Fatalf("want 2 attempts, got %d", attempts)
+	}
+}
+
+func TestOnConflictWithOptionsStopsOnNonConflictError(t *testing.T) {
+	wantErr := errors.New("boom")
+
+	attempts := 0
+	err := OnConflictWithOptions(testOptions(), func() error {
+		attempts++
+		return wantErr
+	})
+
+	if err != wantErr {
+		t.Fatalf("want %v, got %v", wantErr, err)
+	}
+	if attempts != 1 {
+		t.Fatalf("want 1 attempt (no retry on a non-conflict error), got %d", attempts)
+	}
+}
+
+func TestOnConflictWithOptionsGivesUpAfterMaxAttempts(t *testing.T) {
+	opts := testOptions()
+
+	attempts := 0
+	err := OnConflictWithOptions(opts, func() error {
+		attempts++
+		return conflictErr
+	})
+
+	if attempts != opts.MaxAttempts {
+		t.Fatalf("want %d attempts, got %d", opts.MaxAttempts, attempts)
+	}
+	if err == nil || !strings.Contains(err.Error(), "giving up after 3 attempts") {
+		t.Fatalf("want a \"giving up\" error, got: %v", err)
+	}
+}
+
+func TestOnConflictWithOptionsBacksOffExponentially(t *testing.T) {
+	opts := Options{MaxAttempts: 3, InitialBackoff: 10 * time.Millisecond}
+
+	start := time.Now()
+	attempts := 0
+	OnConflictWithOptions(opts, func() error {
+		attempts++
+		return conflictErr
+	})
+	elapsed := time.Since(start)
+
+	// Two sleeps between three attempts: 10ms then 20ms.
+	if elapsed < 30*time.Millisecond {
+		t.Fatalf("expected backoff sleeps to total at least 30ms, elapsed %v", elapsed)
+	}
+}